@@ -0,0 +1,92 @@
+// Package gatt_client is the central/client-role counterpart to
+// ble_gatt_server: it connects to a remote GATT server exposed by BlueZ and
+// walks its service tree instead of hosting one locally.
+package gatt_client
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+)
+
+const (
+	deviceInterface     = "org.bluez.Device1"
+	propertiesInterface = "org.freedesktop.DBus.Properties"
+
+	servicesResolvedPollInterval = 200 * time.Millisecond
+)
+
+// Client represents a connection to a single remote BLE peripheral.
+type Client struct {
+	Bus    *dbus.Conn
+	Device dbus.ObjectPath
+}
+
+// Connect brings up a connection to a remote device identified either by its
+// MAC address ("AA:BB:CC:DD:EE:FF") or by its full BlueZ object path
+// (e.g. "/org/bluez/hci0/dev_AA_BB_CC_DD_EE_FF"), then blocks until
+// Device1.ServicesResolved goes true (or ctx is done).
+func Connect(ctx context.Context, bus *dbus.Conn, adapterDevice, target string) (*Client, error) {
+	path := devicePath(adapterDevice, target)
+
+	obj := bus.Object("org.bluez", path)
+	if call := obj.Call(deviceInterface+".Connect", 0); call.Err != nil {
+		return nil, fmt.Errorf("failed to connect to %s: %v", path, call.Err)
+	}
+
+	client := &Client{Bus: bus, Device: path}
+	if err := client.waitServicesResolved(ctx); err != nil {
+		return nil, err
+	}
+
+	return client, nil
+}
+
+// devicePath resolves target to a BlueZ object path. A target that already
+// looks like an object path ("/...") is used as-is; otherwise it is treated
+// as a MAC address under adapterDevice (e.g. "hci0").
+func devicePath(adapterDevice, target string) dbus.ObjectPath {
+	if strings.HasPrefix(target, "/") {
+		return dbus.ObjectPath(target)
+	}
+
+	mangled := strings.ReplaceAll(target, ":", "_")
+	return dbus.ObjectPath(fmt.Sprintf("/org/bluez/%s/dev_%s", adapterDevice, mangled))
+}
+
+// waitServicesResolved polls Device1.ServicesResolved until it is true or ctx
+// is done. BlueZ does not guarantee the property is set the instant Connect
+// returns, so callers must wait for it before reading the GATT tree.
+func (c *Client) waitServicesResolved(ctx context.Context) error {
+	obj := c.Bus.Object("org.bluez", c.Device)
+
+	ticker := time.NewTicker(servicesResolvedPollInterval)
+	defer ticker.Stop()
+
+	for {
+		resolved, err := obj.GetProperty(deviceInterface + ".ServicesResolved")
+		if err == nil {
+			if v, ok := resolved.Value().(bool); ok && v {
+				return nil
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("timed out waiting for services to resolve on %s: %w", c.Device, ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
+
+// Disconnect tears down the connection to the remote device.
+func (c *Client) Disconnect() error {
+	obj := c.Bus.Object("org.bluez", c.Device)
+	if call := obj.Call(deviceInterface+".Disconnect", 0); call.Err != nil {
+		return fmt.Errorf("failed to disconnect %s: %v", c.Device, call.Err)
+	}
+	return nil
+}
@@ -0,0 +1,169 @@
+package gatt_client
+
+import (
+	"fmt"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// RemoteService is a GattService1 discovered on a remote device.
+type RemoteService struct {
+	Bus             *dbus.Conn
+	Path            dbus.ObjectPath
+	UUID            string
+	Primary         bool
+	Characteristics []*RemoteCharacteristic
+}
+
+// RemoteCharacteristic is a GattCharacteristic1 discovered under a RemoteService.
+type RemoteCharacteristic struct {
+	Bus         *dbus.Conn
+	Path        dbus.ObjectPath
+	Service     *RemoteService
+	UUID        string
+	Flags       []string
+	Descriptors []*RemoteDescriptor
+
+	// notifySignals is the channel registered with Bus.Signal by StartNotify,
+	// and notifyMatchRule the AddMatch rule that feeds it; StopNotify needs
+	// both to tear down the subscription it created. nil when not notifying.
+	notifySignals   chan *dbus.Signal
+	notifyMatchRule string
+}
+
+// RemoteDescriptor is a GattDescriptor1 discovered under a RemoteCharacteristic.
+type RemoteDescriptor struct {
+	Bus            *dbus.Conn
+	Path           dbus.ObjectPath
+	Characteristic *RemoteCharacteristic
+	UUID           string
+}
+
+func (c *RemoteCharacteristic) object() dbus.BusObject {
+	return c.Bus.Object("org.bluez", c.Path)
+}
+
+func (d *RemoteDescriptor) object() dbus.BusObject {
+	return d.Bus.Object("org.bluez", d.Path)
+}
+
+// ReadValue reads the characteristic's current value.
+func (c *RemoteCharacteristic) ReadValue() ([]byte, error) {
+	var value []byte
+	call := c.object().Call(gattCharacteristicInterface+".ReadValue", 0, map[string]dbus.Variant{})
+	if call.Err != nil {
+		return nil, fmt.Errorf("failed to read %s: %v", c.Path, call.Err)
+	}
+	if err := call.Store(&value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+// WriteValue writes value to the characteristic. When withResponse is true it
+// uses BlueZ's "request" write type (acked by the peripheral); otherwise it
+// uses "command" (write-without-response).
+func (c *RemoteCharacteristic) WriteValue(value []byte, withResponse bool) error {
+	writeType := "command"
+	if withResponse {
+		writeType = "request"
+	}
+
+	options := map[string]dbus.Variant{"type": dbus.MakeVariant(writeType)}
+	call := c.object().Call(gattCharacteristicInterface+".WriteValue", 0, value, options)
+	if call.Err != nil {
+		return fmt.Errorf("failed to write %s: %v", c.Path, call.Err)
+	}
+	return nil
+}
+
+// StartNotify enables notifications/indications on the characteristic and
+// returns a channel that receives each new Value as it arrives via
+// PropertiesChanged. The channel is closed by StopNotify. Calling StartNotify
+// again before StopNotify replaces the previous subscription.
+func (c *RemoteCharacteristic) StartNotify() (<-chan []byte, error) {
+	if call := c.object().Call(gattCharacteristicInterface+".StartNotify", 0); call.Err != nil {
+		return nil, fmt.Errorf("failed to start notifications on %s: %v", c.Path, call.Err)
+	}
+
+	if c.notifySignals != nil {
+		c.Bus.BusObject().Call("org.freedesktop.DBus.RemoveMatch", 0, c.notifyMatchRule)
+		c.Bus.RemoveSignal(c.notifySignals)
+		close(c.notifySignals)
+	}
+
+	values := make(chan []byte, 16)
+	signals := make(chan *dbus.Signal, 16)
+	c.Bus.Signal(signals)
+
+	matchRule := fmt.Sprintf(
+		"type='signal',interface='%s',member='PropertiesChanged',path='%s'",
+		propertiesInterface, c.Path,
+	)
+	c.Bus.BusObject().Call("org.freedesktop.DBus.AddMatch", 0, matchRule)
+	c.notifySignals = signals
+	c.notifyMatchRule = matchRule
+
+	go func() {
+		defer close(values)
+		for sig := range signals {
+			if sig.Path != c.Path || len(sig.Body) < 2 {
+				continue
+			}
+			changed, ok := sig.Body[1].(map[string]dbus.Variant)
+			if !ok {
+				continue
+			}
+			if v, ok := changed["Value"]; ok {
+				if b, ok := v.Value().([]byte); ok {
+					values <- b
+				}
+			}
+		}
+	}()
+
+	return values, nil
+}
+
+// StopNotify disables notifications/indications on the characteristic and
+// tears down the D-Bus signal subscription StartNotify registered, closing
+// the channel it returned.
+func (c *RemoteCharacteristic) StopNotify() error {
+	if call := c.object().Call(gattCharacteristicInterface+".StopNotify", 0); call.Err != nil {
+		return fmt.Errorf("failed to stop notifications on %s: %v", c.Path, call.Err)
+	}
+
+	if c.notifySignals == nil {
+		return nil
+	}
+
+	c.Bus.BusObject().Call("org.freedesktop.DBus.RemoveMatch", 0, c.notifyMatchRule)
+	c.Bus.RemoveSignal(c.notifySignals)
+	close(c.notifySignals)
+	c.notifySignals = nil
+	c.notifyMatchRule = ""
+
+	return nil
+}
+
+// ReadValue reads the descriptor's current value.
+func (d *RemoteDescriptor) ReadValue() ([]byte, error) {
+	var value []byte
+	call := d.object().Call(gattDescriptorInterface+".ReadValue", 0, map[string]dbus.Variant{})
+	if call.Err != nil {
+		return nil, fmt.Errorf("failed to read %s: %v", d.Path, call.Err)
+	}
+	if err := call.Store(&value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+// WriteValue writes value to the descriptor.
+func (d *RemoteDescriptor) WriteValue(value []byte) error {
+	call := d.object().Call(gattDescriptorInterface+".WriteValue", 0, value, map[string]dbus.Variant{})
+	if call.Err != nil {
+		return fmt.Errorf("failed to write %s: %v", d.Path, call.Err)
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+package gatt_client
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/godbus/dbus/v5"
+)
+
+const adapterInterface = "org.bluez.Adapter1"
+
+// ScanFilter narrows SetDiscoveryFilter to BLE devices advertising at least
+// one of UUIDs (an empty slice matches everything).
+type ScanFilter struct {
+	UUIDs     []string
+	Transport string // defaults to "le" when empty
+}
+
+// Device is a remote device discovered during a scan.
+type Device struct {
+	Path    dbus.ObjectPath
+	Address string
+	Name    string
+}
+
+// Adapter wraps a local BlueZ adapter (e.g. "hci0") for client-role use.
+type Adapter struct {
+	Path dbus.ObjectPath
+	Bus  *dbus.Conn
+}
+
+// NewAdapter returns an Adapter wrapper for the given hci device.
+func NewAdapter(bus *dbus.Conn, device string) *Adapter {
+	return &Adapter{
+		Path: dbus.ObjectPath(fmt.Sprintf("/org/bluez/%s", device)),
+		Bus:  bus,
+	}
+}
+
+// Scan applies filter via SetDiscoveryFilter, starts discovery, and streams
+// newly discovered devices on the returned channel until ctx is done, at
+// which point discovery is stopped and the channel is closed.
+func (a *Adapter) Scan(ctx context.Context, filter ScanFilter) (<-chan Device, error) {
+	transport := filter.Transport
+	if transport == "" {
+		transport = "le"
+	}
+
+	options := map[string]dbus.Variant{
+		"Transport": dbus.MakeVariant(transport),
+	}
+	if len(filter.UUIDs) > 0 {
+		options["UUIDs"] = dbus.MakeVariant(filter.UUIDs)
+	}
+
+	adapter := a.Bus.Object("org.bluez", a.Path)
+	if call := adapter.Call(adapterInterface+".SetDiscoveryFilter", 0, options); call.Err != nil {
+		return nil, fmt.Errorf("failed to set discovery filter: %v", call.Err)
+	}
+	if call := adapter.Call(adapterInterface+".StartDiscovery", 0); call.Err != nil {
+		return nil, fmt.Errorf("failed to start discovery: %v", call.Err)
+	}
+
+	signals := make(chan *dbus.Signal, 16)
+	a.Bus.Signal(signals)
+	matchRule := "type='signal',interface='org.freedesktop.DBus.ObjectManager',member='InterfacesAdded'"
+	a.Bus.BusObject().Call("org.freedesktop.DBus.AddMatch", 0, matchRule)
+
+	devices := make(chan Device, 16)
+
+	go func() {
+		defer close(devices)
+		defer a.Bus.RemoveSignal(signals)
+		defer adapter.Call(adapterInterface+".StopDiscovery", 0)
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case sig, ok := <-signals:
+				if !ok {
+					return
+				}
+				device, ok := deviceFromInterfacesAdded(a.Path, sig)
+				if ok {
+					devices <- device
+				}
+			}
+		}
+	}()
+
+	return devices, nil
+}
+
+func deviceFromInterfacesAdded(adapterPath dbus.ObjectPath, sig *dbus.Signal) (Device, bool) {
+	if len(sig.Body) < 2 {
+		return Device{}, false
+	}
+	path, ok := sig.Body[0].(dbus.ObjectPath)
+	if !ok || !strings.HasPrefix(string(path), string(adapterPath)+"/dev_") {
+		return Device{}, false
+	}
+	ifaces, ok := sig.Body[1].(map[string]map[string]dbus.Variant)
+	if !ok {
+		return Device{}, false
+	}
+	props, ok := ifaces[deviceInterface]
+	if !ok {
+		return Device{}, false
+	}
+
+	device := Device{Path: path}
+	if v, ok := props["Address"]; ok {
+		device.Address, _ = v.Value().(string)
+	}
+	if v, ok := props["Name"]; ok {
+		device.Name, _ = v.Value().(string)
+	}
+	return device, true
+}
@@ -0,0 +1,168 @@
+package gatt_client
+
+import (
+	"fmt"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/godbus/dbus/v5/introspect"
+)
+
+const (
+	gattServiceInterface        = "org.bluez.GattService1"
+	gattCharacteristicInterface = "org.bluez.GattCharacteristic1"
+	gattDescriptorInterface     = "org.bluez.GattDescriptor1"
+)
+
+// Services walks the BlueZ object tree rooted at the device path by
+// introspection, returning every GattService1 node (and, nested underneath
+// each, its characteristics and descriptors) found below it.
+func (c *Client) Services() ([]*RemoteService, error) {
+	children, err := introspectChildren(c.Bus, c.Device)
+	if err != nil {
+		return nil, fmt.Errorf("failed to introspect %s: %v", c.Device, err)
+	}
+
+	var services []*RemoteService
+	for _, childPath := range children {
+		if !hasInterface(c.Bus, childPath, gattServiceInterface) {
+			continue
+		}
+
+		service, err := c.buildService(childPath)
+		if err != nil {
+			return nil, err
+		}
+		services = append(services, service)
+	}
+
+	return services, nil
+}
+
+func (c *Client) buildService(path dbus.ObjectPath) (*RemoteService, error) {
+	props, err := getAll(c.Bus, path, gattServiceInterface)
+	if err != nil {
+		return nil, err
+	}
+
+	service := &RemoteService{
+		Bus:     c.Bus,
+		Path:    path,
+		UUID:    variantString(props["UUID"]),
+		Primary: variantBool(props["Primary"]),
+	}
+
+	children, err := introspectChildren(c.Bus, path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to introspect %s: %v", path, err)
+	}
+
+	for _, childPath := range children {
+		if !hasInterface(c.Bus, childPath, gattCharacteristicInterface) {
+			continue
+		}
+
+		chrc, err := c.buildCharacteristic(childPath, service)
+		if err != nil {
+			return nil, err
+		}
+		service.Characteristics = append(service.Characteristics, chrc)
+	}
+
+	return service, nil
+}
+
+func (c *Client) buildCharacteristic(path dbus.ObjectPath, service *RemoteService) (*RemoteCharacteristic, error) {
+	props, err := getAll(c.Bus, path, gattCharacteristicInterface)
+	if err != nil {
+		return nil, err
+	}
+
+	chrc := &RemoteCharacteristic{
+		Bus:     c.Bus,
+		Path:    path,
+		Service: service,
+		UUID:    variantString(props["UUID"]),
+		Flags:   variantStringSlice(props["Flags"]),
+	}
+
+	children, err := introspectChildren(c.Bus, path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to introspect %s: %v", path, err)
+	}
+
+	for _, childPath := range children {
+		if !hasInterface(c.Bus, childPath, gattDescriptorInterface) {
+			continue
+		}
+
+		descProps, err := getAll(c.Bus, childPath, gattDescriptorInterface)
+		if err != nil {
+			return nil, err
+		}
+
+		chrc.Descriptors = append(chrc.Descriptors, &RemoteDescriptor{
+			Bus:            c.Bus,
+			Path:           childPath,
+			Characteristic: chrc,
+			UUID:           variantString(descProps["UUID"]),
+		})
+	}
+
+	return chrc, nil
+}
+
+// introspectChildren returns the full object paths of path's immediate
+// children, per the traversal BlueZ documents for clients that don't want to
+// rely solely on ObjectManager.
+func introspectChildren(bus *dbus.Conn, path dbus.ObjectPath) ([]dbus.ObjectPath, error) {
+	node, err := introspect.Call(bus.Object("org.bluez", path))
+	if err != nil {
+		return nil, err
+	}
+
+	children := make([]dbus.ObjectPath, 0, len(node.Children))
+	for _, child := range node.Children {
+		children = append(children, dbus.ObjectPath(string(path)+"/"+child.Name))
+	}
+	return children, nil
+}
+
+func hasInterface(bus *dbus.Conn, path dbus.ObjectPath, iface string) bool {
+	node, err := introspect.Call(bus.Object("org.bluez", path))
+	if err != nil {
+		return false
+	}
+	for _, i := range node.Interfaces {
+		if i.Name == iface {
+			return true
+		}
+	}
+	return false
+}
+
+func getAll(bus *dbus.Conn, path dbus.ObjectPath, iface string) (map[string]dbus.Variant, error) {
+	var props map[string]dbus.Variant
+	call := bus.Object("org.bluez", path).Call(propertiesInterface+".GetAll", 0, iface)
+	if call.Err != nil {
+		return nil, call.Err
+	}
+	if err := call.Store(&props); err != nil {
+		return nil, err
+	}
+	return props, nil
+}
+
+func variantString(v dbus.Variant) string {
+	s, _ := v.Value().(string)
+	return s
+}
+
+func variantBool(v dbus.Variant) bool {
+	b, _ := v.Value().(bool)
+	return b
+}
+
+func variantStringSlice(v dbus.Variant) []string {
+	s, _ := v.Value().([]string)
+	return s
+}
@@ -3,7 +3,6 @@ package gatt_server
 import (
 	"fmt"
 	"github.com/godbus/dbus/v5"
-	"github.com/godbus/dbus/v5/introspect"
 	"github.com/godbus/dbus/v5/prop"
 )
 
@@ -32,6 +31,7 @@ type Advertisement struct {
 	ServiceData    map[string][]byte
 	LocalName      string
 	IncludeTxPower bool
+	Props          *prop.Properties
 }
 
 // NewAdvertisement creates a new advertisement
@@ -76,6 +76,7 @@ type Service struct {
 	UUID          string
 	Primary       bool
 	Characteristics []*Characteristic
+	Props          *prop.Properties
 }
 
 // NewService creates a new service
@@ -103,7 +104,9 @@ func (s *Service) GetProperties() map[string]dbus.Variant {
 	return props
 }
 
-// Characteristic represents a GATT characteristic
+// Characteristic represents a GATT characteristic. Flags accepts the plain
+// BlueZ access flags ("read", "write", "write-without-response", "notify",
+// "indicate") as well as the security flags in SecureFlags.
 type Characteristic struct {
 	Path        dbus.ObjectPath
 	Bus         *dbus.Conn
@@ -113,6 +116,7 @@ type Characteristic struct {
 	Descriptors []*Descriptor
 	Value       []byte
 	Notifying   bool
+	Props       *prop.Properties
 }
 
 // NewCharacteristic creates a new characteristic
@@ -132,17 +136,20 @@ func (c *Characteristic) GetProperties() map[string]dbus.Variant {
 	props["Service"] = dbus.MakeVariant(c.Service.Path)
 	props["UUID"] = dbus.MakeVariant(c.UUID)
 	props["Flags"] = dbus.MakeVariant(c.Flags)
-	
+	props["Value"] = dbus.MakeVariant(c.Value)
+	props["Notifying"] = dbus.MakeVariant(c.Notifying)
+
 	paths := make([]dbus.ObjectPath, len(c.Descriptors))
 	for i, desc := range c.Descriptors {
 		paths[i] = desc.Path
 	}
 	props["Descriptors"] = dbus.MakeVariant(paths)
-	
+
 	return props
 }
 
-// Descriptor represents a GATT descriptor
+// Descriptor represents a GATT descriptor. Flags accepts the same plain and
+// security flags as Characteristic.Flags.
 type Descriptor struct {
 	Path           dbus.ObjectPath
 	Bus            *dbus.Conn
@@ -150,6 +157,7 @@ type Descriptor struct {
 	Characteristic *Characteristic
 	Flags          []string
 	Value          []byte
+	Props          *prop.Properties
 }
 
 // NewDescriptor creates a new descriptor
@@ -215,74 +223,61 @@ func (a *Advertisement) RegisterAdvertisement() error {
 	return call.Err
 }
 
-// SetupInterfaces sets up the D-Bus interfaces for all objects
+// SetupInterfaces sets up the D-Bus interfaces for all objects, including
+// their org.freedesktop.DBus.Properties handlers (via prop.Export, so
+// PropertiesChanged is emitted automatically instead of by hand).
 func SetupInterfaces(bus *dbus.Conn, app *Application, adv *Advertisement) error {
 	// Setup application
 	if err := bus.Export(app, app.Path, "org.bluez.GattApplication1"); err != nil {
 		return err
 	}
-	
+
+	// Setup ObjectManager so BlueZ can enumerate the tree via GetManagedObjects
+	if err := bus.Export(app, app.Path, objectManagerInterface); err != nil {
+		return err
+	}
+
+	appProps, err := prop.Export(bus, app.Path, app.propSpec())
+	if err != nil {
+		return fmt.Errorf("failed to export application properties: %v", err)
+	}
+	app.Props = appProps
+
 	// Setup advertisement
 	if err := bus.Export(adv, adv.Path, "org.bluez.LEAdvertisement1"); err != nil {
 		return err
 	}
-	
-	// Setup services
+
+	advProps, err := prop.Export(bus, adv.Path, adv.propSpec())
+	if err != nil {
+		return fmt.Errorf("failed to export advertisement properties: %v", err)
+	}
+	adv.Props = advProps
+
+	// Setup services (and everything nested underneath them)
 	for _, service := range app.Services {
-		if err := bus.Export(service, service.Path, "org.bluez.GattService1"); err != nil {
+		if err := exportService(bus, service); err != nil {
 			return err
 		}
-		
-		// Setup characteristics
-		for _, characteristic := range service.Characteristics {
-			if err := bus.Export(characteristic, characteristic.Path, "org.bluez.GattCharacteristic1"); err != nil {
-				return err
-			}
-			
-			// Setup descriptors
-			for _, descriptor := range characteristic.Descriptors {
-				if err := bus.Export(descriptor, descriptor.Path, "org.bluez.GattDescriptor1"); err != nil {
-					return err
-				}
-			}
-		}
 	}
-	
+
 	return nil
 }
 
-// SetupIntrospection sets up the D-Bus introspection for all objects
+// SetupIntrospection sets up the D-Bus introspection for the application and
+// advertisement. Services (and everything nested underneath them) get their
+// introspection wired up by exportService, called from SetupInterfaces and
+// from Application.AddService.
 func SetupIntrospection(bus *dbus.Conn, app *Application, adv *Advertisement) error {
 	// Setup application introspection
-	if err := bus.Export(introspect.NewIntrospectable(app), app.Path, "org.freedesktop.DBus.Introspectable"); err != nil {
+	if err := bus.Export(newIntrospectable("org.bluez.GattApplication1"), app.Path, "org.freedesktop.DBus.Introspectable"); err != nil {
 		return err
 	}
-	
+
 	// Setup advertisement introspection
-	if err := bus.Export(introspect.NewIntrospectable(adv), adv.Path, "org.freedesktop.DBus.Introspectable"); err != nil {
+	if err := bus.Export(newIntrospectable("org.bluez.LEAdvertisement1"), adv.Path, "org.freedesktop.DBus.Introspectable"); err != nil {
 		return err
 	}
-	
-	// Setup services introspection
-	for _, service := range app.Services {
-		if err := bus.Export(introspect.NewIntrospectable(service), service.Path, "org.freedesktop.DBus.Introspectable"); err != nil {
-			return err
-		}
-		
-		// Setup characteristics introspection
-		for _, characteristic := range service.Characteristics {
-			if err := bus.Export(introspect.NewIntrospectable(characteristic), characteristic.Path, "org.freedesktop.DBus.Introspectable"); err != nil {
-				return err
-			}
-			
-			// Setup descriptors introspection
-			for _, descriptor := range characteristic.Descriptors {
-				if err := bus.Export(introspect.NewIntrospectable(descriptor), descriptor.Path, "org.freedesktop.DBus.Introspectable"); err != nil {
-					return err
-				}
-			}
-		}
-	}
-	
+
 	return nil
 } 
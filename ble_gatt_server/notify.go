@@ -0,0 +1,55 @@
+package gatt_server
+
+import (
+	"fmt"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// hasFlag reports whether want is present in flags.
+func hasFlag(flags []string, want string) bool {
+	for _, flag := range flags {
+		if flag == want {
+			return true
+		}
+	}
+	return false
+}
+
+// SendNotification pushes value as the characteristic's new Value, emitting
+// PropertiesChanged for subscribers. c must have notify or indicate in its
+// Flags and currently be Notifying (i.e. a client has called StartNotify).
+func (c *Characteristic) SendNotification(value []byte) error {
+	if !c.Notifying {
+		return fmt.Errorf("notifications not enabled")
+	}
+	if !hasFlag(c.Flags, "notify") && !hasFlag(c.Flags, "indicate") {
+		return fmt.Errorf("characteristic does not support notify/indicate")
+	}
+
+	c.Value = value
+	if c.Props != nil {
+		c.Props.SetMust("org.bluez.GattCharacteristic1", "Value", dbus.MakeVariant(c.Value))
+	}
+	return nil
+}
+
+// StartNotify implements org.bluez.GattCharacteristic1.StartNotify, called by
+// BlueZ when a remote central subscribes to notifications/indications.
+func (c *Characteristic) StartNotify() error {
+	c.Notifying = true
+	if c.Props != nil {
+		c.Props.SetMust("org.bluez.GattCharacteristic1", "Notifying", dbus.MakeVariant(c.Notifying))
+	}
+	return nil
+}
+
+// StopNotify implements org.bluez.GattCharacteristic1.StopNotify, called by
+// BlueZ when a remote central unsubscribes.
+func (c *Characteristic) StopNotify() error {
+	c.Notifying = false
+	if c.Props != nil {
+		c.Props.SetMust("org.bluez.GattCharacteristic1", "Notifying", dbus.MakeVariant(c.Notifying))
+	}
+	return nil
+}
@@ -0,0 +1,199 @@
+package gatt_server
+
+import (
+	"fmt"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/godbus/dbus/v5/prop"
+)
+
+// objectManagerInterface is the well-known BlueZ/D-Bus interface that lets a
+// client enumerate an entire object tree (services, characteristics,
+// descriptors) in a single call instead of walking Introspect output.
+const objectManagerInterface = "org.freedesktop.DBus.ObjectManager"
+
+// GetManagedObjects implements org.freedesktop.DBus.ObjectManager.GetManagedObjects.
+// BlueZ calls this on the application path right after RegisterApplication to
+// discover the GATT tree, so it must reflect every exported service,
+// characteristic, and descriptor.
+func (a *Application) GetManagedObjects() (map[dbus.ObjectPath]map[string]map[string]dbus.Variant, error) {
+	objects := make(map[dbus.ObjectPath]map[string]map[string]dbus.Variant)
+
+	for _, service := range a.Services {
+		mergeManagedObjects(objects, managedObjectsFor(service))
+	}
+
+	return objects, nil
+}
+
+// managedObjectsFor builds the ObjectManager entries for a service and
+// everything nested underneath it (characteristics, descriptors).
+func managedObjectsFor(service *Service) map[dbus.ObjectPath]map[string]map[string]dbus.Variant {
+	objects := make(map[dbus.ObjectPath]map[string]map[string]dbus.Variant)
+
+	objects[service.Path] = map[string]map[string]dbus.Variant{
+		"org.bluez.GattService1": service.GetProperties(),
+	}
+
+	for _, chrc := range service.Characteristics {
+		objects[chrc.Path] = map[string]map[string]dbus.Variant{
+			"org.bluez.GattCharacteristic1": chrc.GetProperties(),
+		}
+
+		for _, desc := range chrc.Descriptors {
+			objects[desc.Path] = map[string]map[string]dbus.Variant{
+				"org.bluez.GattDescriptor1": desc.GetProperties(),
+			}
+		}
+	}
+
+	return objects
+}
+
+func mergeManagedObjects(dst, src map[dbus.ObjectPath]map[string]map[string]dbus.Variant) {
+	for path, ifaces := range src {
+		dst[path] = ifaces
+	}
+}
+
+// exportService exports a service, and everything nested underneath it, on
+// the bus along with their introspection data and a prop.Properties handler
+// (so PropertiesChanged is emitted for Value/Notifying, see notify.go). It is
+// shared by the initial static setup (SetupInterfaces) and
+// by AddService so a service registered at runtime looks identical to one
+// registered at startup.
+func exportService(bus *dbus.Conn, service *Service) error {
+	if err := bus.Export(service, service.Path, "org.bluez.GattService1"); err != nil {
+		return fmt.Errorf("failed to export service %s: %v", service.Path, err)
+	}
+	if err := bus.Export(newIntrospectable("org.bluez.GattService1"), service.Path, "org.freedesktop.DBus.Introspectable"); err != nil {
+		return fmt.Errorf("failed to export service introspection %s: %v", service.Path, err)
+	}
+	serviceProps, err := prop.Export(bus, service.Path, service.propSpec())
+	if err != nil {
+		return fmt.Errorf("failed to export service properties %s: %v", service.Path, err)
+	}
+	service.Props = serviceProps
+
+	for _, chrc := range service.Characteristics {
+		if err := bus.Export(chrc, chrc.Path, "org.bluez.GattCharacteristic1"); err != nil {
+			return fmt.Errorf("failed to export characteristic %s: %v", chrc.Path, err)
+		}
+		if err := bus.Export(newIntrospectable("org.bluez.GattCharacteristic1"), chrc.Path, "org.freedesktop.DBus.Introspectable"); err != nil {
+			return fmt.Errorf("failed to export characteristic introspection %s: %v", chrc.Path, err)
+		}
+		chrcProps, err := prop.Export(bus, chrc.Path, chrc.propSpec())
+		if err != nil {
+			return fmt.Errorf("failed to export characteristic properties %s: %v", chrc.Path, err)
+		}
+		chrc.Props = chrcProps
+
+		for _, desc := range chrc.Descriptors {
+			if err := bus.Export(desc, desc.Path, "org.bluez.GattDescriptor1"); err != nil {
+				return fmt.Errorf("failed to export descriptor %s: %v", desc.Path, err)
+			}
+			if err := bus.Export(newIntrospectable("org.bluez.GattDescriptor1"), desc.Path, "org.freedesktop.DBus.Introspectable"); err != nil {
+				return fmt.Errorf("failed to export descriptor introspection %s: %v", desc.Path, err)
+			}
+			descProps, err := prop.Export(bus, desc.Path, desc.propSpec())
+			if err != nil {
+				return fmt.Errorf("failed to export descriptor properties %s: %v", desc.Path, err)
+			}
+			desc.Props = descProps
+		}
+	}
+
+	return nil
+}
+
+// unexportService removes a service and everything nested underneath it from
+// the bus, the mirror image of exportService.
+func unexportService(bus *dbus.Conn, service *Service) error {
+	for _, chrc := range service.Characteristics {
+		for _, desc := range chrc.Descriptors {
+			if err := bus.Export(nil, desc.Path, "org.freedesktop.DBus.Properties"); err != nil {
+				return fmt.Errorf("failed to unexport descriptor properties %s: %v", desc.Path, err)
+			}
+			if err := bus.Export(nil, desc.Path, "org.freedesktop.DBus.Introspectable"); err != nil {
+				return fmt.Errorf("failed to unexport descriptor introspection %s: %v", desc.Path, err)
+			}
+			if err := bus.Export(nil, desc.Path, "org.bluez.GattDescriptor1"); err != nil {
+				return fmt.Errorf("failed to unexport descriptor %s: %v", desc.Path, err)
+			}
+		}
+		if err := bus.Export(nil, chrc.Path, "org.freedesktop.DBus.Properties"); err != nil {
+			return fmt.Errorf("failed to unexport characteristic properties %s: %v", chrc.Path, err)
+		}
+		if err := bus.Export(nil, chrc.Path, "org.freedesktop.DBus.Introspectable"); err != nil {
+			return fmt.Errorf("failed to unexport characteristic introspection %s: %v", chrc.Path, err)
+		}
+		if err := bus.Export(nil, chrc.Path, "org.bluez.GattCharacteristic1"); err != nil {
+			return fmt.Errorf("failed to unexport characteristic %s: %v", chrc.Path, err)
+		}
+	}
+	if err := bus.Export(nil, service.Path, "org.freedesktop.DBus.Properties"); err != nil {
+		return fmt.Errorf("failed to unexport service properties %s: %v", service.Path, err)
+	}
+	if err := bus.Export(nil, service.Path, "org.freedesktop.DBus.Introspectable"); err != nil {
+		return fmt.Errorf("failed to unexport service introspection %s: %v", service.Path, err)
+	}
+	if err := bus.Export(nil, service.Path, "org.bluez.GattService1"); err != nil {
+		return fmt.Errorf("failed to unexport service %s: %v", service.Path, err)
+	}
+
+	return nil
+}
+
+// AddService exports service on the bus, appends it to the application, and
+// emits InterfacesAdded for it and everything nested underneath it so BlueZ
+// (and any other ObjectManager client) picks it up without a restart.
+func (a *Application) AddService(service *Service) error {
+	if err := exportService(a.Bus, service); err != nil {
+		return err
+	}
+
+	a.Services = append(a.Services, service)
+
+	for path, ifaces := range managedObjectsFor(service) {
+		if err := a.Bus.Emit(a.Path, objectManagerInterface+".InterfacesAdded", path, ifaces); err != nil {
+			return fmt.Errorf("failed to emit InterfacesAdded for %s: %v", path, err)
+		}
+	}
+
+	return nil
+}
+
+// RemoveService unexports service, drops it from the application, and emits
+// InterfacesRemoved for it and everything nested underneath it.
+func (a *Application) RemoveService(service *Service) error {
+	index := -1
+	for i, s := range a.Services {
+		if s == service {
+			index = i
+			break
+		}
+	}
+	if index == -1 {
+		return fmt.Errorf("service %s is not registered on this application", service.Path)
+	}
+
+	removed := managedObjectsFor(service)
+
+	if err := unexportService(a.Bus, service); err != nil {
+		return err
+	}
+
+	a.Services = append(a.Services[:index], a.Services[index+1:]...)
+
+	for path, ifaces := range removed {
+		ifaceNames := make([]string, 0, len(ifaces))
+		for name := range ifaces {
+			ifaceNames = append(ifaceNames, name)
+		}
+		if err := a.Bus.Emit(a.Path, objectManagerInterface+".InterfacesRemoved", path, ifaceNames); err != nil {
+			return fmt.Errorf("failed to emit InterfacesRemoved for %s: %v", path, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,108 @@
+package gatt_server
+
+import (
+	"github.com/godbus/dbus/v5"
+	"github.com/godbus/dbus/v5/introspect"
+	"github.com/godbus/dbus/v5/prop"
+)
+
+// newIntrospectable builds a minimal org.freedesktop.DBus.Introspectable
+// handler advertising the standard Introspectable/Properties interfaces plus
+// iface. BlueZ discovers the GATT tree via ObjectManager.GetManagedObjects,
+// not Introspect, so this only needs to be correct enough that generic D-Bus
+// tooling (busctl, d-feet, ...) can introspect the path without choking on
+// it. iface is listed by name only, with no methods or properties.
+func newIntrospectable(iface string) introspect.Introspectable {
+	return introspect.NewIntrospectable(&introspect.Node{
+		Interfaces: []introspect.Interface{
+			introspect.IntrospectData,
+			prop.IntrospectData,
+			{Name: iface},
+		},
+	})
+}
+
+// propSpec builds the prop.Map describing org.bluez.GattService1, ready to
+// hand to prop.Export.
+func (s *Service) propSpec() prop.Map {
+	return prop.Map{
+		"org.bluez.GattService1": {
+			"UUID":            {Value: s.UUID, Writable: false, Emit: prop.EmitFalse},
+			"Primary":         {Value: s.Primary, Writable: false, Emit: prop.EmitFalse},
+			"Characteristics": {Value: characteristicPaths(s), Writable: false, Emit: prop.EmitFalse},
+		},
+	}
+}
+
+// propSpec builds the prop.Map describing org.bluez.GattCharacteristic1.
+// Value and Notifying emit PropertiesChanged on every change (see
+// SendNotification/StartNotify/StopNotify in notify.go); the rest are set
+// once at registration time and never change.
+func (c *Characteristic) propSpec() prop.Map {
+	return prop.Map{
+		"org.bluez.GattCharacteristic1": {
+			"Service":     {Value: c.Service.Path, Writable: false, Emit: prop.EmitFalse},
+			"UUID":        {Value: c.UUID, Writable: false, Emit: prop.EmitFalse},
+			"Flags":       {Value: c.Flags, Writable: false, Emit: prop.EmitFalse},
+			"Descriptors": {Value: descriptorPaths(c), Writable: false, Emit: prop.EmitFalse},
+			"Value":       {Value: c.Value, Writable: false, Emit: prop.EmitTrue},
+			"Notifying":   {Value: c.Notifying, Writable: false, Emit: prop.EmitTrue},
+		},
+	}
+}
+
+// propSpec builds the prop.Map describing org.bluez.GattDescriptor1.
+func (d *Descriptor) propSpec() prop.Map {
+	return prop.Map{
+		"org.bluez.GattDescriptor1": {
+			"Characteristic": {Value: d.Characteristic.Path, Writable: false, Emit: prop.EmitFalse},
+			"UUID":           {Value: d.UUID, Writable: false, Emit: prop.EmitFalse},
+			"Flags":          {Value: d.Flags, Writable: false, Emit: prop.EmitFalse},
+			"Value":          {Value: d.Value, Writable: false, Emit: prop.EmitTrue},
+		},
+	}
+}
+
+// propSpec builds the prop.Map describing org.bluez.LEAdvertisement1.
+func (a *Advertisement) propSpec() prop.Map {
+	return prop.Map{
+		"org.bluez.LEAdvertisement1": {
+			"Type":           {Value: a.Type, Writable: false, Emit: prop.EmitFalse},
+			"LocalName":      {Value: a.LocalName, Writable: false, Emit: prop.EmitFalse},
+			"IncludeTxPower": {Value: a.IncludeTxPower, Writable: false, Emit: prop.EmitFalse},
+		},
+	}
+}
+
+// propSpec builds the prop.Map describing org.bluez.GattApplication1.
+func (a *Application) propSpec() prop.Map {
+	return prop.Map{
+		"org.bluez.GattApplication1": {
+			"Services": {Value: servicePaths(a), Writable: false, Emit: prop.EmitFalse},
+		},
+	}
+}
+
+func characteristicPaths(s *Service) []dbus.ObjectPath {
+	paths := make([]dbus.ObjectPath, len(s.Characteristics))
+	for i, chrc := range s.Characteristics {
+		paths[i] = chrc.Path
+	}
+	return paths
+}
+
+func descriptorPaths(c *Characteristic) []dbus.ObjectPath {
+	paths := make([]dbus.ObjectPath, len(c.Descriptors))
+	for i, desc := range c.Descriptors {
+		paths[i] = desc.Path
+	}
+	return paths
+}
+
+func servicePaths(a *Application) []dbus.ObjectPath {
+	paths := make([]dbus.ObjectPath, len(a.Services))
+	for i, service := range a.Services {
+		paths[i] = service.Path
+	}
+	return paths
+}
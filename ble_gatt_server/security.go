@@ -0,0 +1,243 @@
+package gatt_server
+
+import (
+	"fmt"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// SecureFlags are the BlueZ characteristic/descriptor flags that require
+// pairing/bonding or explicit per-request authorization. They are additive to
+// the plain "read"/"write"/"notify"/"indicate" flags already accepted by
+// NewCharacteristic/NewDescriptor.
+var SecureFlags = []string{
+	"encrypt-read",
+	"encrypt-write",
+	"encrypt-authenticated-read",
+	"encrypt-authenticated-write",
+	"secure-read",
+	"secure-write",
+	"authorize",
+}
+
+const agentInterface = "org.bluez.Agent1"
+
+// AgentCapability is the IO capability string passed to
+// AgentManager1.RegisterAgent, e.g. "KeyboardDisplay" or "NoInputNoOutput".
+type AgentCapability string
+
+const (
+	CapabilityDisplayOnly     AgentCapability = "DisplayOnly"
+	CapabilityDisplayYesNo    AgentCapability = "DisplayYesNo"
+	CapabilityKeyboardOnly    AgentCapability = "KeyboardOnly"
+	CapabilityNoInputNoOutput AgentCapability = "NoInputNoOutput"
+	CapabilityKeyboardDisplay AgentCapability = "KeyboardDisplay"
+)
+
+// Agent implements org.bluez.Agent1 with pluggable callbacks. A nil
+// RequestPasskey, RequestConfirmation, AuthorizeService, RequestPinCode or
+// RequestAuthorization rejects the request; a nil DisplayPasskey or
+// DisplayPinCode is a no-op that reports success.
+type Agent struct {
+	Path       dbus.ObjectPath
+	Bus        *dbus.Conn
+	Capability AgentCapability
+
+	RequestPasskey       func(device dbus.ObjectPath) (uint32, error)
+	DisplayPasskey        func(device dbus.ObjectPath, passkey uint32, entered uint16) error
+	RequestConfirmation   func(device dbus.ObjectPath, passkey uint32) error
+	AuthorizeService      func(device dbus.ObjectPath, uuid string) error
+
+	// RequestPinCode/DisplayPinCode are the legacy-pairing (pre-SSP)
+	// equivalents of RequestPasskey/DisplayPasskey, used for devices that
+	// only support PIN-code pairing.
+	RequestPinCode func(device dbus.ObjectPath) (string, error)
+	DisplayPinCode func(device dbus.ObjectPath, pincode string) error
+
+	// RequestAuthorization is called to confirm an incoming pairing/bonding
+	// request that doesn't go through the passkey/PIN flows (e.g. "just
+	// works" pairing initiated by the remote device).
+	RequestAuthorization func(device dbus.ObjectPath) error
+}
+
+// NewAgent creates a new pairing agent at the conventional BlueZ agent path.
+func NewAgent(bus *dbus.Conn, capability AgentCapability) *Agent {
+	return &Agent{
+		Path:       dbus.ObjectPath("/org/bluez/example/agent0"),
+		Bus:        bus,
+		Capability: capability,
+	}
+}
+
+// Register exports the agent and registers it with BlueZ's AgentManager1,
+// marking it as the default agent for requests not tied to a specific device.
+func (ag *Agent) Register() error {
+	// The *DBus methods are exported under their org.bluez.Agent1 names; the
+	// unsuffixed names are taken by the callback fields.
+	methods := map[string]string{
+		"RequestPasskeyDBus":       "RequestPasskey",
+		"DisplayPasskeyDBus":       "DisplayPasskey",
+		"RequestConfirmationDBus":  "RequestConfirmation",
+		"AuthorizeServiceDBus":     "AuthorizeService",
+		"RequestPinCodeDBus":       "RequestPinCode",
+		"DisplayPinCodeDBus":       "DisplayPinCode",
+		"RequestAuthorizationDBus": "RequestAuthorization",
+	}
+	if err := ag.Bus.ExportWithMap(ag, methods, ag.Path, agentInterface); err != nil {
+		return fmt.Errorf("failed to export agent: %v", err)
+	}
+
+	manager := ag.Bus.Object("org.bluez", dbus.ObjectPath("/org/bluez"))
+	if call := manager.Call("org.bluez.AgentManager1.RegisterAgent", 0, ag.Path, string(ag.Capability)); call.Err != nil {
+		return fmt.Errorf("failed to register agent: %v", call.Err)
+	}
+	if call := manager.Call("org.bluez.AgentManager1.RequestDefaultAgent", 0, ag.Path); call.Err != nil {
+		return fmt.Errorf("failed to request default agent: %v", call.Err)
+	}
+
+	return nil
+}
+
+// Unregister removes the agent from BlueZ and unexports it.
+func (ag *Agent) Unregister() error {
+	manager := ag.Bus.Object("org.bluez", dbus.ObjectPath("/org/bluez"))
+	if call := manager.Call("org.bluez.AgentManager1.UnregisterAgent", 0, ag.Path); call.Err != nil {
+		return fmt.Errorf("failed to unregister agent: %v", call.Err)
+	}
+	return ag.Bus.Export(nil, ag.Path, agentInterface)
+}
+
+// RequestPasskeyDBus is the org.bluez.Agent1.RequestPasskey method.
+func (ag *Agent) RequestPasskeyDBus(device dbus.ObjectPath) (uint32, *dbus.Error) {
+	if ag.RequestPasskey == nil {
+		return 0, dbus.MakeFailedError(fmt.Errorf("RequestPasskey not implemented"))
+	}
+	passkey, err := ag.RequestPasskey(device)
+	if err != nil {
+		return 0, dbus.MakeFailedError(err)
+	}
+	return passkey, nil
+}
+
+// DisplayPasskeyDBus is the org.bluez.Agent1.DisplayPasskey method.
+func (ag *Agent) DisplayPasskeyDBus(device dbus.ObjectPath, passkey uint32, entered uint16) *dbus.Error {
+	if ag.DisplayPasskey == nil {
+		return nil
+	}
+	if err := ag.DisplayPasskey(device, passkey, entered); err != nil {
+		return dbus.MakeFailedError(err)
+	}
+	return nil
+}
+
+// RequestConfirmationDBus is the org.bluez.Agent1.RequestConfirmation method.
+func (ag *Agent) RequestConfirmationDBus(device dbus.ObjectPath, passkey uint32) *dbus.Error {
+	if ag.RequestConfirmation == nil {
+		return dbus.MakeFailedError(fmt.Errorf("RequestConfirmation not implemented"))
+	}
+	if err := ag.RequestConfirmation(device, passkey); err != nil {
+		return dbus.MakeFailedError(err)
+	}
+	return nil
+}
+
+// AuthorizeServiceDBus is the org.bluez.Agent1.AuthorizeService method.
+func (ag *Agent) AuthorizeServiceDBus(device dbus.ObjectPath, uuid string) *dbus.Error {
+	if ag.AuthorizeService == nil {
+		return dbus.MakeFailedError(fmt.Errorf("AuthorizeService not implemented"))
+	}
+	if err := ag.AuthorizeService(device, uuid); err != nil {
+		return dbus.MakeFailedError(err)
+	}
+	return nil
+}
+
+// RequestPinCodeDBus is the org.bluez.Agent1.RequestPinCode method.
+func (ag *Agent) RequestPinCodeDBus(device dbus.ObjectPath) (string, *dbus.Error) {
+	if ag.RequestPinCode == nil {
+		return "", dbus.MakeFailedError(fmt.Errorf("RequestPinCode not implemented"))
+	}
+	pincode, err := ag.RequestPinCode(device)
+	if err != nil {
+		return "", dbus.MakeFailedError(err)
+	}
+	return pincode, nil
+}
+
+// DisplayPinCodeDBus is the org.bluez.Agent1.DisplayPinCode method.
+func (ag *Agent) DisplayPinCodeDBus(device dbus.ObjectPath, pincode string) *dbus.Error {
+	if ag.DisplayPinCode == nil {
+		return nil
+	}
+	if err := ag.DisplayPinCode(device, pincode); err != nil {
+		return dbus.MakeFailedError(err)
+	}
+	return nil
+}
+
+// RequestAuthorizationDBus is the org.bluez.Agent1.RequestAuthorization
+// method, called by BlueZ to confirm an incoming pairing/bonding request
+// that doesn't go through the passkey/PIN flows.
+func (ag *Agent) RequestAuthorizationDBus(device dbus.ObjectPath) *dbus.Error {
+	if ag.RequestAuthorization == nil {
+		return dbus.MakeFailedError(fmt.Errorf("RequestAuthorization not implemented"))
+	}
+	if err := ag.RequestAuthorization(device); err != nil {
+		return dbus.MakeFailedError(err)
+	}
+	return nil
+}
+
+// Cancel is the org.bluez.Agent1.Cancel method, called by BlueZ when a
+// pending request is no longer needed.
+func (ag *Agent) Cancel() *dbus.Error {
+	return nil
+}
+
+// Release is the org.bluez.Agent1.Release method, called when the agent is
+// unregistered (including when bluetoothd itself shuts down).
+func (ag *Agent) Release() *dbus.Error {
+	return nil
+}
+
+// Adapter wraps org.bluez.Adapter1's pairing-related properties so callers
+// don't need to poke raw D-Bus property paths to make a peripheral
+// discoverable and pairable.
+type Adapter struct {
+	Path dbus.ObjectPath
+	Bus  *dbus.Conn
+}
+
+// NewAdapter returns an Adapter wrapper for the given hci device, e.g. "hci0".
+func NewAdapter(bus *dbus.Conn, device string) *Adapter {
+	return &Adapter{
+		Path: dbus.ObjectPath(fmt.Sprintf("/org/bluez/%s", device)),
+		Bus:  bus,
+	}
+}
+
+func (a *Adapter) object() dbus.BusObject {
+	return a.Bus.Object("org.bluez", a.Path)
+}
+
+// SetPairable sets the Adapter1.Pairable property.
+func (a *Adapter) SetPairable(pairable bool) error {
+	return a.object().SetProperty("org.bluez.Adapter1.Pairable", pairable)
+}
+
+// SetDiscoverable sets the Adapter1.Discoverable property.
+func (a *Adapter) SetDiscoverable(discoverable bool) error {
+	return a.object().SetProperty("org.bluez.Adapter1.Discoverable", discoverable)
+}
+
+// SetPairableTimeout sets the Adapter1.PairableTimeout property, in seconds.
+// A value of 0 disables the timeout.
+func (a *Adapter) SetPairableTimeout(seconds uint32) error {
+	return a.object().SetProperty("org.bluez.Adapter1.PairableTimeout", seconds)
+}
+
+// SetAlias sets the Adapter1.Alias property, the friendly name BlueZ
+// advertises for this adapter.
+func (a *Adapter) SetAlias(alias string) error {
+	return a.object().SetProperty("org.bluez.Adapter1.Alias", alias)
+}
@@ -6,12 +6,18 @@ import (
 	"log"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
 	"time"
 
 	"github.com/godbus/dbus/v5"
+	"github.com/godbus/dbus/v5/prop"
 )
 
+// defaultATTMTU is the minimum ATT_MTU guaranteed by the Bluetooth spec
+// before any MTU exchange happens.
+const defaultATTMTU = 23
+
 const (
 	DEVICE_NAME        = "Sync-Sonic"
 	SERVICE_UUID       = "19b10000-e8f2-537e-4f6c-d104768a1214"
@@ -31,6 +37,13 @@ const (
 	CONNECTION_CHECK_INTERVAL = 1 * time.Second
 )
 
+// longWriteQuietPeriod is how long WriteValue waits after a fragment before
+// treating the assembled value as complete and dispatching it. The ATT
+// long-write procedure has no "this is the last fragment" signal — the
+// client's first fragment is always sent at offset 0, not the last one — so
+// completion has to be detected by quiescence instead of by offset.
+const longWriteQuietPeriod = 20 * time.Millisecond
+
 // Characteristic represents a GATT characteristic
 type Characteristic struct {
 	Path        dbus.ObjectPath
@@ -41,6 +54,33 @@ type Characteristic struct {
 	Value       []byte
 	Notifying   bool
 	PingCount   uint32
+
+	// MTU is the ATT MTU negotiated with the connected device, as reported
+	// by BlueZ in the "mtu" read/write option. It defaults to the minimum
+	// BLE ATT MTU until a client reports a larger one.
+	MTU uint16
+
+	// writeFD and notifyFD back AcquireWrite/AcquireNotify: once acquired,
+	// writes/notifications flow over these sockets instead of WriteValue
+	// calls and PropertiesChanged signals.
+	writeFD  *os.File
+	notifyFD *os.File
+
+	// Props is the exported org.freedesktop.DBus.Properties handler for
+	// this characteristic, built by Application.Start. SendNotification and
+	// StartNotify/StopNotify push changes through it so PropertiesChanged is
+	// emitted automatically instead of by hand.
+	Props *prop.Properties
+
+	// Router, if set, turns WriteValue into a general-purpose RPC dispatcher
+	// (see router.go) instead of the fixed PING protocol below.
+	Router *Router
+
+	// writeMu guards Value and writeTimer: WriteValue may be called
+	// concurrently by BlueZ and by the AcquireWrite goroutine, and
+	// writeTimer's AfterFunc runs on its own goroutine.
+	writeMu    sync.Mutex
+	writeTimer *time.Timer
 }
 
 // Service represents a GATT service
@@ -50,6 +90,7 @@ type Service struct {
 	UUID          string
 	Primary       bool
 	Characteristic *Characteristic
+	Props         *prop.Properties
 }
 
 // Advertisement represents a BLE advertisement
@@ -59,6 +100,7 @@ type Advertisement struct {
 	Type           string
 	LocalName      string
 	IncludeTxPower bool
+	Props          *prop.Properties
 }
 
 // Application represents the main GATT application
@@ -69,15 +111,7 @@ type Application struct {
 	Advertisement *Advertisement
 	Connected     bool
 	StopChan      chan struct{}
-}
-
-// DBusObject interface defines the methods required for D-Bus objects
-type DBusObject interface {
-	GetAll(iface string) (map[string]dbus.Variant, error)
-	Get(iface, prop string) (dbus.Variant, error)
-	Set(iface, prop string, value dbus.Variant) error
-	GetDBusPath() dbus.ObjectPath
-	GetDBusInterface() string
+	Props         *prop.Properties
 }
 
 // NewCharacteristic creates a new characteristic
@@ -88,6 +122,7 @@ func NewCharacteristic(bus *dbus.Conn, service *Service) *Characteristic {
 		UUID:    CHARACTERISTIC_UUID,
 		Service: service,
 		Flags:   []string{"read", "write", "notify"},
+		MTU:     defaultATTMTU,
 	}
 }
 
@@ -126,48 +161,117 @@ func NewApplication(bus *dbus.Conn) *Application {
 	}
 }
 
-// GetProperties returns the characteristic properties
-func (c *Characteristic) GetProperties() map[string]dbus.Variant {
-	props := make(map[string]dbus.Variant)
-	props["Service"] = dbus.MakeVariant(c.Service.Path)
-	props["UUID"] = dbus.MakeVariant(c.UUID)
-	props["Flags"] = dbus.MakeVariant(c.Flags)
-	return props
+// propSpec builds the prop.Map describing org.bluez.GattCharacteristic1,
+// ready to hand to prop.Export. Value and Notifying emit PropertiesChanged on
+// every change; the rest are set once at registration time and never change.
+func (c *Characteristic) propSpec() prop.Map {
+	return prop.Map{
+		"org.bluez.GattCharacteristic1": {
+			"UUID":      {Value: c.UUID, Writable: false, Emit: prop.EmitFalse},
+			"Service":   {Value: c.Service.Path, Writable: false, Emit: prop.EmitFalse},
+			"Flags":     {Value: c.Flags, Writable: false, Emit: prop.EmitFalse},
+			"Value":     {Value: c.Value, Writable: false, Emit: prop.EmitTrue},
+			"Notifying": {Value: c.Notifying, Writable: false, Emit: prop.EmitTrue},
+		},
+	}
 }
 
-// GetProperties returns the service properties
-func (s *Service) GetProperties() map[string]dbus.Variant {
-	props := make(map[string]dbus.Variant)
-	props["UUID"] = dbus.MakeVariant(s.UUID)
-	props["Primary"] = dbus.MakeVariant(s.Primary)
-	props["Characteristics"] = dbus.MakeVariant([]dbus.ObjectPath{s.Characteristic.Path})
-	return props
+// propSpec builds the prop.Map describing org.bluez.GattService1.
+func (s *Service) propSpec() prop.Map {
+	return prop.Map{
+		"org.bluez.GattService1": {
+			"UUID":            {Value: s.UUID, Writable: false, Emit: prop.EmitFalse},
+			"Primary":         {Value: s.Primary, Writable: false, Emit: prop.EmitFalse},
+			"Characteristics": {Value: []dbus.ObjectPath{s.Characteristic.Path}, Writable: false, Emit: prop.EmitFalse},
+		},
+	}
 }
 
-// GetProperties returns the advertisement properties
-func (a *Advertisement) GetProperties() map[string]dbus.Variant {
-	props := make(map[string]dbus.Variant)
-	props["Type"] = dbus.MakeVariant(a.Type)
-	props["LocalName"] = dbus.MakeVariant(a.LocalName)
-	props["IncludeTxPower"] = dbus.MakeVariant(a.IncludeTxPower)
-	return props
+// propSpec builds the prop.Map describing org.bluez.LEAdvertisement1.
+func (a *Advertisement) propSpec() prop.Map {
+	return prop.Map{
+		"org.bluez.LEAdvertisement1": {
+			"Type":           {Value: a.Type, Writable: false, Emit: prop.EmitFalse},
+			"LocalName":      {Value: a.LocalName, Writable: false, Emit: prop.EmitFalse},
+			"IncludeTxPower": {Value: a.IncludeTxPower, Writable: false, Emit: prop.EmitFalse},
+		},
+	}
 }
 
-// GetProperties returns the application properties
-func (app *Application) GetProperties() map[string]dbus.Variant {
-	props := make(map[string]dbus.Variant)
-	props["Services"] = dbus.MakeVariant([]dbus.ObjectPath{app.Service.Path})
-	return props
+// propSpec builds the prop.Map describing org.bluez.GattApplication1.
+func (app *Application) propSpec() prop.Map {
+	return prop.Map{
+		"org.bluez.GattApplication1": {
+			"Services": {Value: []dbus.ObjectPath{app.Service.Path}, Writable: false, Emit: prop.EmitFalse},
+		},
+	}
 }
 
-// ReadValue handles read requests
+// ReadValue handles read requests. It honors the "offset" and "mtu" entries
+// BlueZ passes in options so long reads (reads that don't fit in a single
+// ATT_MTU) can be served across multiple calls.
 func (c *Characteristic) ReadValue(options map[string]dbus.Variant) ([]byte, error) {
-	log.Println("Read request received")
-	return []byte("Hello from Pi!"), nil
+	c.trackOptions(options)
+
+	offset := optionUint16(options, "offset")
+	data := []byte("Hello from Pi!")
+	if len(c.Value) > 0 {
+		data = c.Value
+	}
+
+	if int(offset) > len(data) {
+		return nil, fmt.Errorf("offset %d exceeds value length %d", offset, len(data))
+	}
+
+	log.Printf("Read request received (offset=%d)", offset)
+	return data[offset:], nil
 }
 
-// WriteValue handles write requests
+// WriteValue handles write requests. It honors "offset" for ATT-level long
+// writes, merging each chunk into c.Value at the given offset (offset 0
+// starts a new message, discarding whatever was previously assembled).
+// Dispatch can't be keyed off offset==0, since the client's first fragment
+// is always sent at offset 0 too; instead, dispatchWrite runs once
+// longWriteQuietPeriod has passed without another fragment arriving.
 func (c *Characteristic) WriteValue(value []byte, options map[string]dbus.Variant) error {
+	c.trackOptions(options)
+	offset := optionUint16(options, "offset")
+
+	c.writeMu.Lock()
+	c.Value = mergeAtOffset(c.Value, value, int(offset), offset == 0)
+	if c.writeTimer != nil {
+		c.writeTimer.Stop()
+	}
+	c.writeTimer = time.AfterFunc(longWriteQuietPeriod, c.dispatchWrite)
+	c.writeMu.Unlock()
+
+	return nil
+}
+
+// dispatchWrite hands a fully-assembled write to Router (if one is
+// attached) for app-level seq/total reassembly and dispatch, or to the fixed
+// PING protocol below. It runs on writeTimer's own goroutine, after
+// WriteValue has decided no further long-write fragment is coming, so any
+// error can no longer be returned to the D-Bus caller and is logged instead.
+func (c *Characteristic) dispatchWrite() {
+	c.writeMu.Lock()
+	value := c.Value
+	c.writeMu.Unlock()
+
+	if err := c.handleMessage(value); err != nil {
+		fmt.Fprintf(os.Stderr, "write dispatch error: %v\n", err)
+	}
+}
+
+// handleMessage processes one fully-assembled write.
+func (c *Characteristic) handleMessage(value []byte) error {
+	if c.Router != nil {
+		if err := c.Router.HandleFrame(value); err != nil {
+			return fmt.Errorf("router dispatch failed: %v", err)
+		}
+		return nil
+	}
+
 	if len(value) < 5 {
 		return fmt.Errorf("invalid message length")
 	}
@@ -198,45 +302,33 @@ func (c *Characteristic) WriteValue(value []byte, options map[string]dbus.Varian
 	return nil
 }
 
-// SendNotification sends a notification to the client
+// SendNotification sends value to the client. If the characteristic supports
+// "notify" or "indicate", and the value is no longer than MTU-3, it is
+// delivered as a single PropertiesChanged signal; otherwise it is split into
+// MTU-3 sized fragments and delivered as a sequence of PropertiesChanged
+// signals (or, once AcquireNotify has handed out a socket, written directly
+// to it). PropertiesChanged is emitted by c.Props, not by hand.
 func (c *Characteristic) SendNotification(value []byte) error {
 	if !c.Notifying {
 		return fmt.Errorf("notifications not enabled")
 	}
-
-	props := make(map[string]dbus.Variant)
-	props["Value"] = dbus.MakeVariant(value)
-
-	return c.Bus.Emit(c.Path, "org.freedesktop.DBus.Properties.PropertiesChanged",
-		"org.bluez.GattCharacteristic1", props, []string{})
-}
-
-// Implement D-Bus interface methods for Application
-func (app *Application) GetAll(iface string) (map[string]dbus.Variant, error) {
-	if iface == "org.bluez.GattApplication1" {
-		return app.GetProperties(), nil
+	if !hasFlag(c.Flags, "notify") && !hasFlag(c.Flags, "indicate") {
+		return fmt.Errorf("characteristic does not support notify/indicate")
 	}
-	return nil, fmt.Errorf("unknown interface: %s", iface)
-}
 
-func (app *Application) Get(iface, prop string) (dbus.Variant, error) {
-	props := app.GetProperties()
-	if v, ok := props[prop]; ok {
-		return v, nil
+	if c.notifyFD != nil {
+		return writeFragments(c.notifyFD, value, c.fragmentSize())
 	}
-	return dbus.Variant{}, fmt.Errorf("unknown property: %s", prop)
-}
-
-func (app *Application) Set(iface, prop string, value dbus.Variant) error {
-	return fmt.Errorf("property %s is read-only", prop)
-}
 
-func (app *Application) GetDBusPath() dbus.ObjectPath {
-	return app.Path
-}
+	for _, fragment := range fragmentValue(value, c.fragmentSize()) {
+		// SetMust, not Set: Value is Writable: false so external peers can't
+		// set it, but that same gate would make Set reject our own
+		// server-initiated update with ErrReadOnly. SetMust bypasses the
+		// gate and still emits PropertiesChanged per the prop.Map's Emit.
+		c.Props.SetMust("org.bluez.GattCharacteristic1", "Value", dbus.MakeVariant(fragment))
+	}
 
-func (app *Application) GetDBusInterface() string {
-	return "org.bluez.GattApplication1"
+	return nil
 }
 
 // Start starts the application
@@ -272,29 +364,39 @@ func (app *Application) Start() error {
 	}
 	log.Println("Advertisement exported successfully")
 
-	// Export properties interface for each object
+	// Export the org.freedesktop.DBus.Properties interface for each object
+	// via prop.Export, so GetAll/Get/Set and PropertiesChanged are handled
+	// by the godbus prop package instead of by hand.
 	log.Println("Exporting application properties...")
-	if err := app.Bus.Export(app, app.Path, "org.freedesktop.DBus.Properties"); err != nil {
+	appProps, err := prop.Export(app.Bus, app.Path, app.propSpec())
+	if err != nil {
 		return fmt.Errorf("failed to export application properties: %v", err)
 	}
+	app.Props = appProps
 	log.Println("Application properties exported successfully")
 
 	log.Println("Exporting service properties...")
-	if err := app.Bus.Export(app.Service, app.Service.Path, "org.freedesktop.DBus.Properties"); err != nil {
+	serviceProps, err := prop.Export(app.Bus, app.Service.Path, app.Service.propSpec())
+	if err != nil {
 		return fmt.Errorf("failed to export service properties: %v", err)
 	}
+	app.Service.Props = serviceProps
 	log.Println("Service properties exported successfully")
 
 	log.Println("Exporting characteristic properties...")
-	if err := app.Bus.Export(app.Service.Characteristic, app.Service.Characteristic.Path, "org.freedesktop.DBus.Properties"); err != nil {
+	chrcProps, err := prop.Export(app.Bus, app.Service.Characteristic.Path, app.Service.Characteristic.propSpec())
+	if err != nil {
 		return fmt.Errorf("failed to export characteristic properties: %v", err)
 	}
+	app.Service.Characteristic.Props = chrcProps
 	log.Println("Characteristic properties exported successfully")
 
 	log.Println("Exporting advertisement properties...")
-	if err := app.Bus.Export(app.Advertisement, app.Advertisement.Path, "org.freedesktop.DBus.Properties"); err != nil {
+	advProps, err := prop.Export(app.Bus, app.Advertisement.Path, app.Advertisement.propSpec())
+	if err != nil {
 		return fmt.Errorf("failed to export advertisement properties: %v", err)
 	}
+	app.Advertisement.Props = advProps
 	log.Println("Advertisement properties exported successfully")
 
 	// Register application on hci0
@@ -400,90 +502,6 @@ func (app *Application) runConnectionCheck() {
 	}
 }
 
-// Implement D-Bus interface methods for Characteristic
-func (c *Characteristic) GetAll(iface string) (map[string]dbus.Variant, error) {
-	if iface == "org.bluez.GattCharacteristic1" {
-		return c.GetProperties(), nil
-	}
-	return nil, fmt.Errorf("unknown interface: %s", iface)
-}
-
-func (c *Characteristic) Get(iface, prop string) (dbus.Variant, error) {
-	props := c.GetProperties()
-	if v, ok := props[prop]; ok {
-		return v, nil
-	}
-	return dbus.Variant{}, fmt.Errorf("unknown property: %s", prop)
-}
-
-func (c *Characteristic) Set(iface, prop string, value dbus.Variant) error {
-	return fmt.Errorf("property %s is read-only", prop)
-}
-
-func (c *Characteristic) GetDBusPath() dbus.ObjectPath {
-	return c.Path
-}
-
-func (c *Characteristic) GetDBusInterface() string {
-	return "org.bluez.GattCharacteristic1"
-}
-
-// Implement D-Bus interface methods for Service
-func (s *Service) GetAll(iface string) (map[string]dbus.Variant, error) {
-	if iface == "org.bluez.GattService1" {
-		return s.GetProperties(), nil
-	}
-	return nil, fmt.Errorf("unknown interface: %s", iface)
-}
-
-func (s *Service) Get(iface, prop string) (dbus.Variant, error) {
-	props := s.GetProperties()
-	if v, ok := props[prop]; ok {
-		return v, nil
-	}
-	return dbus.Variant{}, fmt.Errorf("unknown property: %s", prop)
-}
-
-func (s *Service) Set(iface, prop string, value dbus.Variant) error {
-	return fmt.Errorf("property %s is read-only", prop)
-}
-
-func (s *Service) GetDBusPath() dbus.ObjectPath {
-	return s.Path
-}
-
-func (s *Service) GetDBusInterface() string {
-	return "org.bluez.GattService1"
-}
-
-// Implement D-Bus interface methods for Advertisement
-func (a *Advertisement) GetAll(iface string) (map[string]dbus.Variant, error) {
-	if iface == "org.bluez.LEAdvertisement1" {
-		return a.GetProperties(), nil
-	}
-	return nil, fmt.Errorf("unknown interface: %s", iface)
-}
-
-func (a *Advertisement) Get(iface, prop string) (dbus.Variant, error) {
-	props := a.GetProperties()
-	if v, ok := props[prop]; ok {
-		return v, nil
-	}
-	return dbus.Variant{}, fmt.Errorf("unknown property: %s", prop)
-}
-
-func (a *Advertisement) Set(iface, prop string, value dbus.Variant) error {
-	return fmt.Errorf("property %s is read-only", prop)
-}
-
-func (a *Advertisement) GetDBusPath() dbus.ObjectPath {
-	return a.Path
-}
-
-func (a *Advertisement) GetDBusInterface() string {
-	return "org.bluez.LEAdvertisement1"
-}
-
 func main() {
 	// Initialize logging with more detail
 	log.SetFlags(log.LstdFlags | log.Lshortfile | log.Lmicroseconds)
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// TestWriteValueReassemblesLongWriteBeforeDispatch is the regression test for
+// the long-write completion bug: a message split across multiple WriteValue
+// calls (the client's first fragment always at offset 0, later fragments at
+// increasing offsets) must be dispatched once, against the fully-assembled
+// value, not against whatever arrived in the first call.
+func TestWriteValueReassemblesLongWriteBeforeDispatch(t *testing.T) {
+	c := &Characteristic{Flags: []string{"write"}}
+	router := NewRouter(c, recordingCodec{})
+	c.Router = router
+
+	var calls [][]byte
+	done := make(chan struct{}, 1)
+	router.Handle("echo", func(payload []byte) ([]byte, error) {
+		calls = append(calls, append([]byte(nil), payload...))
+		done <- struct{}{}
+		return nil, nil
+	})
+
+	full, err := recordingCodec{}.Encode(Envelope{Type: "echo", Payload: []byte("a message too long for one ATT write")})
+	if err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+	frame := encodeFrameHeader(0, 1, 0, full)
+
+	split := len(frame) / 2
+	opts1 := map[string]dbus.Variant{"offset": dbus.MakeVariant(uint16(0))}
+	opts2 := map[string]dbus.Variant{"offset": dbus.MakeVariant(uint16(split))}
+	if err := c.WriteValue(frame[:split], opts1); err != nil {
+		t.Fatalf("WriteValue (fragment 1): %v", err)
+	}
+	if err := c.WriteValue(frame[split:], opts2); err != nil {
+		t.Fatalf("WriteValue (fragment 2): %v", err)
+	}
+
+	select {
+	case <-done:
+	case <-time.After(longWriteQuietPeriod * 10):
+		t.Fatal("handler was never dispatched")
+	}
+
+	if len(calls) != 1 {
+		t.Fatalf("handler called %d times, want 1", len(calls))
+	}
+	if string(calls[0]) != "a message too long for one ATT write" {
+		t.Errorf("dispatched payload = %q, want the fully reassembled message", calls[0])
+	}
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// trackOptions records the negotiated MTU from a ReadValue/WriteValue options
+// dict, so later fragmentation decisions use the real link MTU instead of the
+// default. The "device" option is not recorded.
+func (c *Characteristic) trackOptions(options map[string]dbus.Variant) {
+	if mtu := optionUint16(options, "mtu"); mtu > 0 {
+		c.MTU = mtu
+	}
+}
+
+// fragmentSize is the largest payload that fits in a single ATT notification
+// for the negotiated MTU (3 bytes of ATT header are reserved).
+func (c *Characteristic) fragmentSize() int {
+	if c.MTU <= 3 {
+		return int(defaultATTMTU) - 3
+	}
+	return int(c.MTU) - 3
+}
+
+// optionUint16 reads a uint16-ish BlueZ option (offset, mtu, ...), returning 0
+// if the option is absent or of an unexpected type.
+func optionUint16(options map[string]dbus.Variant, key string) uint16 {
+	v, ok := options[key]
+	if !ok {
+		return 0
+	}
+	switch n := v.Value().(type) {
+	case uint16:
+		return n
+	case uint32:
+		return uint16(n)
+	case uint64:
+		return uint16(n)
+	case int32:
+		return uint16(n)
+	}
+	return 0
+}
+
+// hasFlag reports whether flags contains want.
+func hasFlag(flags []string, want string) bool {
+	for _, f := range flags {
+		if f == want {
+			return true
+		}
+	}
+	return false
+}
+
+// mergeAtOffset writes chunk into buf at offset, growing buf as needed, and
+// returns the resulting slice. This is how a long write's fragments (each
+// delivered as a separate WriteValue call per the GATT long-write procedure)
+// get reassembled into the full value.
+//
+// reset marks the fragment that starts a new message (WriteValue's
+// offset==0 write — the client's long-write procedure always begins at
+// offset 0). On that write buf is resized to exactly offset+len(chunk)
+// rather than only grown, so the new message can't inherit a stale tail from
+// whatever longer value a previous message left in buf.
+func mergeAtOffset(buf, chunk []byte, offset int, reset bool) []byte {
+	end := offset + len(chunk)
+	if end != len(buf) && (reset || end > len(buf)) {
+		grown := make([]byte, end)
+		copy(grown, buf)
+		buf = grown
+	}
+	copy(buf[offset:end], chunk)
+	return buf
+}
+
+// fragmentValue splits value into chunks of at most size bytes. A size <= 0
+// or a value shorter than size is returned as a single fragment.
+func fragmentValue(value []byte, size int) [][]byte {
+	if size <= 0 || len(value) <= size {
+		return [][]byte{value}
+	}
+
+	fragments := make([][]byte, 0, (len(value)+size-1)/size)
+	for len(value) > 0 {
+		n := size
+		if n > len(value) {
+			n = len(value)
+		}
+		fragments = append(fragments, value[:n])
+		value = value[n:]
+	}
+	return fragments
+}
+
+// writeFragments writes value to f in size-sized chunks.
+func writeFragments(f *os.File, value []byte, size int) error {
+	for _, fragment := range fragmentValue(value, size) {
+		if _, err := f.Write(fragment); err != nil {
+			return fmt.Errorf("failed to write fragment: %v", err)
+		}
+	}
+	return nil
+}
+
+// StartNotify is called by BlueZ when the client subscribes to notifications
+// or indications (via writing the Client Characteristic Configuration
+// descriptor). It only takes effect if the characteristic was built with a
+// "notify" or "indicate" flag.
+func (c *Characteristic) StartNotify() error {
+	if !hasFlag(c.Flags, "notify") && !hasFlag(c.Flags, "indicate") {
+		return fmt.Errorf("characteristic does not support notify/indicate")
+	}
+	c.Notifying = true
+	return c.setNotifying(true)
+}
+
+// StopNotify is called by BlueZ when the client unsubscribes.
+func (c *Characteristic) StopNotify() error {
+	c.Notifying = false
+	return c.setNotifying(false)
+}
+
+// AcquireWrite hands out a socket for write-without-response, avoiding a
+// WriteValue D-Bus round trip per packet. Requires the "write-without-response"
+// flag. Returns the remote end of a socket pair and the current MTU; the
+// local end is pumped into WriteValue-style handling in the background.
+func (c *Characteristic) AcquireWrite(options map[string]dbus.Variant) (dbus.UnixFD, uint16, *dbus.Error) {
+	if !hasFlag(c.Flags, "write-without-response") {
+		return 0, 0, dbus.MakeFailedError(fmt.Errorf("characteristic does not support write-without-response"))
+	}
+	c.trackOptions(options)
+
+	fds, err := syscall.Socketpair(syscall.AF_UNIX, syscall.SOCK_SEQPACKET, 0)
+	if err != nil {
+		return 0, 0, dbus.MakeFailedError(err)
+	}
+
+	local := os.NewFile(uintptr(fds[0]), "gatt-acquire-write")
+	c.writeFD = local
+
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, err := local.Read(buf)
+			if err != nil {
+				return
+			}
+			if err := c.WriteValue(append([]byte(nil), buf[:n]...), map[string]dbus.Variant{}); err != nil {
+				fmt.Fprintf(os.Stderr, "acquired write dispatch error: %v\n", err)
+			}
+		}
+	}()
+
+	return dbus.UnixFD(fds[1]), c.MTU, nil
+}
+
+// AcquireNotify hands out a socket for notifications, avoiding a
+// PropertiesChanged signal per packet. Requires the "notify" flag. Returns
+// the remote end of a socket pair and the current MTU.
+func (c *Characteristic) AcquireNotify(options map[string]dbus.Variant) (dbus.UnixFD, uint16, *dbus.Error) {
+	if !hasFlag(c.Flags, "notify") {
+		return 0, 0, dbus.MakeFailedError(fmt.Errorf("characteristic does not support notify"))
+	}
+	c.trackOptions(options)
+
+	fds, err := syscall.Socketpair(syscall.AF_UNIX, syscall.SOCK_SEQPACKET, 0)
+	if err != nil {
+		return 0, 0, dbus.MakeFailedError(err)
+	}
+
+	c.notifyFD = os.NewFile(uintptr(fds[0]), "gatt-acquire-notify")
+	c.Notifying = true
+	if err := c.setNotifying(true); err != nil {
+		return 0, 0, dbus.MakeFailedError(err)
+	}
+
+	return dbus.UnixFD(fds[1]), c.MTU, nil
+}
+
+// setNotifying pushes the Notifying property through c.Props so
+// PropertiesChanged is emitted, if properties have been exported yet (they
+// have not, e.g. during tests that construct a Characteristic directly).
+func (c *Characteristic) setNotifying(notifying bool) error {
+	if c.Props == nil {
+		return nil
+	}
+	// SetMust, not Set: Notifying is Writable: false, and Set enforces that
+	// against our own server-initiated update too, returning ErrReadOnly.
+	c.Props.SetMust("org.bluez.GattCharacteristic1", "Notifying", dbus.MakeVariant(notifying))
+	return nil
+}
@@ -0,0 +1,65 @@
+package main
+
+import "testing"
+
+func TestMergeAtOffset(t *testing.T) {
+	tests := []struct {
+		name   string
+		buf    []byte
+		chunk  []byte
+		offset int
+		reset  bool
+		want   []byte
+	}{
+		{
+			name:   "first fragment of a long write grows an empty buffer",
+			buf:    nil,
+			chunk:  []byte("hello"),
+			offset: 0,
+			reset:  true,
+			want:   []byte("hello"),
+		},
+		{
+			name:   "later fragment at a higher offset grows the buffer",
+			buf:    []byte("hello"),
+			chunk:  []byte("world"),
+			offset: 5,
+			reset:  false,
+			want:   []byte("helloworld"),
+		},
+		{
+			name:   "reset on a new message truncates a longer previous value",
+			buf:    []byte("a longer previous message"),
+			chunk:  []byte("short"),
+			offset: 0,
+			reset:  true,
+			want:   []byte("short"),
+		},
+		{
+			name:   "non-reset write never shrinks the buffer",
+			buf:    []byte("a longer previous message"),
+			chunk:  []byte("mid"),
+			offset: 2,
+			reset:  false,
+			want:   []byte("a midger previous message"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := mergeAtOffset(append([]byte(nil), tt.buf...), tt.chunk, tt.offset, tt.reset)
+			if string(got) != string(tt.want) {
+				t.Errorf("mergeAtOffset(%q, %q, %d, %v) = %q, want %q", tt.buf, tt.chunk, tt.offset, tt.reset, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMergeAtOffsetNonResetOverwritesInPlace(t *testing.T) {
+	buf := []byte("0123456789")
+	got := mergeAtOffset(buf, []byte("XY"), 3, false)
+	want := "012XY56789"
+	if string(got) != want {
+		t.Errorf("mergeAtOffset overwrite = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sync"
+)
+
+// frameHeaderSize is the size, in bytes, of the seq/total/flags header Router
+// prepends to every fragment it reassembles or emits. It sits underneath the
+// codec-encoded Envelope, so a single logical message can span more than one
+// ATT notification/write without the codec needing to know about chunking.
+const frameHeaderSize = 5 // seq (uint16) + total (uint16) + flags (uint8)
+
+// Envelope is the codec-encoded wrapper around every message Router
+// dispatches: Type selects the registered Handler, Payload is that handler's
+// own codec-encoded request or response.
+type Envelope struct {
+	Type    string `json:"type" cbor:"type"`
+	Payload []byte `json:"payload" cbor:"payload"`
+}
+
+// Handler processes one decoded request payload and returns the response
+// payload to send back (nil for no response), both still codec-encoded.
+type Handler func(payload []byte) ([]byte, error)
+
+// Router turns a Characteristic into a general-purpose request/response RPC
+// transport: incoming writes are reassembled from seq/total-framed fragments,
+// decoded with Codec, and dispatched by Envelope.Type; responses are
+// encoded, split back into MTU-sized fragments, and delivered as
+// notifications.
+type Router struct {
+	Characteristic *Characteristic
+	Codec          Codec
+
+	mu              sync.Mutex
+	handlers        map[string]Handler
+	reassembly      map[uint16][]byte
+	reassemblyTotal uint16
+}
+
+// NewRouter creates a Router for characteristic c using codec to encode and
+// decode envelopes and payloads.
+func NewRouter(c *Characteristic, codec Codec) *Router {
+	return &Router{
+		Characteristic: c,
+		Codec:          codec,
+		handlers:       make(map[string]Handler),
+		reassembly:     make(map[uint16][]byte),
+	}
+}
+
+// Handle registers handler for messages of the given Envelope.Type.
+func (r *Router) Handle(messageType string, handler Handler) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.handlers[messageType] = handler
+}
+
+// HandleFrame feeds one seq/total-framed fragment through reassembly,
+// dispatching it once every fragment of its message has arrived. Fragments
+// may arrive across multiple WriteValue calls; HandleFrame is a no-op until
+// the message is complete.
+func (r *Router) HandleFrame(raw []byte) error {
+	seq, total, _, payload, err := decodeFrameHeader(raw)
+	if err != nil {
+		return err
+	}
+	if total == 0 {
+		return fmt.Errorf("frame declares total=0")
+	}
+
+	full, complete := r.reassemble(seq, total, payload)
+	if !complete {
+		return nil
+	}
+
+	return r.dispatch(full)
+}
+
+// reassemble records payload for seq and, once every fragment 0..total-1 has
+// been seen, concatenates and returns them in order.
+//
+// A fragment left over from a previous message that was abandoned before
+// completion (e.g. a client that sent fragment 0 of a 3-fragment message and
+// then gave up) must not satisfy the len(r.reassembly) < total count check
+// for a later, unrelated message and get fed into dispatch as if it were
+// part of it. So the in-progress reassembly is reset whenever seq==0 starts
+// a new message, and also whenever total changes: every fragment carries its
+// message's total, so a different total is itself a signal that whatever
+// was previously accumulating belongs to a different message. (A
+// replacement message with both seq==0 never observed and an identical
+// total to the abandoned one would still collide; closing that fully would
+// need a per-message id in the frame header, which the wire format doesn't
+// carry today.)
+func (r *Router) reassemble(seq, total uint16, payload []byte) ([]byte, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if seq == 0 || total != r.reassemblyTotal {
+		r.reassembly = make(map[uint16][]byte)
+		r.reassemblyTotal = total
+	}
+
+	r.reassembly[seq] = payload
+	if len(r.reassembly) < int(total) {
+		return nil, false
+	}
+
+	full := make([]byte, 0, len(payload)*int(total))
+	for i := uint16(0); i < total; i++ {
+		chunk, ok := r.reassembly[i]
+		if !ok {
+			return nil, false
+		}
+		full = append(full, chunk...)
+	}
+	r.reassembly = make(map[uint16][]byte)
+	r.reassemblyTotal = 0
+	return full, true
+}
+
+// dispatch decodes data as an Envelope and runs the handler registered for
+// its Type, sending back whatever the handler returns.
+func (r *Router) dispatch(data []byte) error {
+	var env Envelope
+	if err := r.Codec.Decode(data, &env); err != nil {
+		return fmt.Errorf("failed to decode envelope: %v", err)
+	}
+
+	r.mu.Lock()
+	handler, ok := r.handlers[env.Type]
+	r.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("no handler registered for message type %q", env.Type)
+	}
+
+	response, err := handler(env.Payload)
+	if err != nil {
+		return fmt.Errorf("handler for %q failed: %v", env.Type, err)
+	}
+	if response == nil {
+		return nil
+	}
+
+	return r.respond(env.Type, response)
+}
+
+// respond encodes payload as an Envelope, splits it into fragments that fit
+// the characteristic's negotiated MTU alongside the frame header, and sends
+// each as a notification.
+func (r *Router) respond(messageType string, payload []byte) error {
+	encoded, err := r.Codec.Encode(Envelope{Type: messageType, Payload: payload})
+	if err != nil {
+		return fmt.Errorf("failed to encode response envelope: %v", err)
+	}
+
+	size := r.Characteristic.fragmentSize() - frameHeaderSize
+	fragments := fragmentValue(encoded, size)
+	total := uint16(len(fragments))
+
+	for seq, fragment := range fragments {
+		frame := encodeFrameHeader(uint16(seq), total, 0, fragment)
+		if err := r.Characteristic.SendNotification(frame); err != nil {
+			return fmt.Errorf("failed to send response fragment %d/%d: %v", seq+1, total, err)
+		}
+	}
+	return nil
+}
+
+// encodeFrameHeader prepends a seq/total/flags header to payload.
+func encodeFrameHeader(seq, total uint16, flags byte, payload []byte) []byte {
+	frame := make([]byte, frameHeaderSize+len(payload))
+	binary.BigEndian.PutUint16(frame[0:2], seq)
+	binary.BigEndian.PutUint16(frame[2:4], total)
+	frame[4] = flags
+	copy(frame[frameHeaderSize:], payload)
+	return frame
+}
+
+// decodeFrameHeader splits raw into its seq/total/flags header and payload.
+func decodeFrameHeader(raw []byte) (seq, total uint16, flags byte, payload []byte, err error) {
+	if len(raw) < frameHeaderSize {
+		return 0, 0, 0, nil, fmt.Errorf("frame too short: %d bytes", len(raw))
+	}
+	seq = binary.BigEndian.Uint16(raw[0:2])
+	total = binary.BigEndian.Uint16(raw[2:4])
+	flags = raw[4]
+	payload = raw[frameHeaderSize:]
+	return seq, total, flags, payload, nil
+}